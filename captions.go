@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gotk3/gotk3/gtk"
+)
+
+// captionRect is the card rectangle the caption background and text are
+// drawn into, in drawing-area coordinates.
+type captionRect struct {
+	X, Y, W, H float64
+}
+
+// captionCardRect computes the caption card rectangle for the given drawing
+// area size, position, and size (strip height for top/bottom, strip width
+// for left/right).
+func captionCardRect(areaWidth, areaHeight, size float64, position string) captionRect {
+	switch position {
+	case "top":
+		return captionRect{X: 0, Y: 0, W: areaWidth, H: size}
+	case "left":
+		return captionRect{X: 0, Y: 0, W: size, H: areaHeight}
+	case "right":
+		return captionRect{X: areaWidth - size, Y: 0, W: size, H: areaHeight}
+	default: // "bottom"
+		return captionRect{X: 0, Y: areaHeight - size, W: areaWidth, H: size}
+	}
+}
+
+// captionAlign maps a caption_position to the horizontal and vertical
+// alignment the text container should use so the text sits inside the card drawn by
+// captionCardRect.
+func captionAlign(position string) (gtk.Align, gtk.Align) {
+	switch position {
+	case "top":
+		return gtk.ALIGN_FILL, gtk.ALIGN_START
+	case "left":
+		return gtk.ALIGN_START, gtk.ALIGN_FILL
+	case "right":
+		return gtk.ALIGN_END, gtk.ALIGN_FILL
+	default: // "bottom"
+		return gtk.ALIGN_FILL, gtk.ALIGN_END
+	}
+}
+
+// truncateCaption limits text to maxLines lines (split on existing newlines
+// or, failing that, a rough per-line character budget) and appends an
+// ellipsis if anything was cut. maxLines <= 0 disables truncation.
+func truncateCaption(text string, maxLines int) string {
+	if maxLines <= 0 {
+		return text
+	}
+
+	const approxCharsPerLine = 48
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > maxLines {
+		return strings.Join(lines[:maxLines], "\n") + "…"
+	}
+
+	limit := maxLines * approxCharsPerLine
+	if len(text) <= limit {
+		return text
+	}
+
+	return strings.TrimSpace(text[:limit]) + "…"
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestCaptionCardRect(t *testing.T) {
+	tests := []struct {
+		position string
+		want     captionRect
+	}{
+		{"bottom", captionRect{X: 0, Y: 80, W: 100, H: 20}},
+		{"top", captionRect{X: 0, Y: 0, W: 100, H: 20}},
+		{"left", captionRect{X: 0, Y: 0, W: 20, H: 100}},
+		{"right", captionRect{X: 80, Y: 0, W: 20, H: 100}},
+		{"", captionRect{X: 0, Y: 80, W: 100, H: 20}}, // unknown falls back to bottom
+	}
+
+	for _, tt := range tests {
+		got := captionCardRect(100, 100, 20, tt.position)
+		if got != tt.want {
+			t.Errorf("captionCardRect(position=%q) = %+v, want %+v", tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateCaption(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxLines int
+		want     string
+	}{
+		{"disabled", "line one\nline two\nline three", 0, "line one\nline two\nline three"},
+		{"under limit", "line one\nline two", 3, "line one\nline two"},
+		{"truncates newlines", "line one\nline two\nline three", 2, "line one\nline two…"},
+	}
+
+	for _, tt := range tests {
+		got := truncateCaption(tt.text, tt.maxLines)
+		if got != tt.want {
+			t.Errorf("%s: truncateCaption(%q, %d) = %q, want %q", tt.name, tt.text, tt.maxLines, got, tt.want)
+		}
+	}
+}
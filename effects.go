@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/gotk3/gotk3/gdk"
+)
+
+// EffectSpec is one step of an effects pipeline, e.g. "grayscale" or
+// {gaussian_blur: 3}. Param holds the effect's single numeric argument
+// (blur radius, dither palette size); effects without a parameter ignore
+// it.
+type EffectSpec struct {
+	Name  string
+	Param int
+}
+
+// UnmarshalYAML accepts both the bare-string form ("grayscale") and the
+// single-key-map form ({gaussian_blur: 3}) used in manifest `effects:`
+// lists.
+func (e *EffectSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		e.Name = name
+		return nil
+	}
+
+	var withParam map[string]int
+	if err := unmarshal(&withParam); err != nil {
+		return err
+	}
+	for name, param := range withParam {
+		e.Name = name
+		e.Param = param
+	}
+	return nil
+}
+
+// effectsSignature turns an effects pipeline into a stable string so it can
+// be folded into the thumbnail cache key.
+func effectsSignature(effects []EffectSpec) string {
+	sig := ""
+	for _, e := range effects {
+		sig += fmt.Sprintf("%s:%d|", e.Name, e.Param)
+	}
+	return sig
+}
+
+// applyEffects runs each effect in effects over pixbuf's pixel buffer in
+// place, left to right.
+func applyEffects(pixbuf *gdk.Pixbuf, effects []EffectSpec) {
+	if len(effects) == 0 {
+		return
+	}
+
+	pixels := pixbuf.GetPixels()
+	width := pixbuf.GetWidth()
+	height := pixbuf.GetHeight()
+	rowstride := pixbuf.GetRowstride()
+	channels := pixbuf.GetNChannels()
+
+	for _, effect := range effects {
+		switch effect.Name {
+		case "grayscale":
+			grayscale(pixels, width, height, rowstride, channels)
+		case "gaussian_blur", "blur":
+			radius := effect.Param
+			if radius <= 0 {
+				radius = 3
+			}
+			gaussianBlur(pixels, width, height, rowstride, channels, radius)
+		case "edge_detect":
+			edgeDetectSobel(pixels, width, height, rowstride, channels)
+		case "ordered_dither":
+			paletteSize := effect.Param
+			if paletteSize <= 1 {
+				paletteSize = 4
+			}
+			orderedDither(pixels, width, height, rowstride, channels, paletteSize)
+		default:
+			log.Printf("effects: unrecognized effect %q, skipping", effect.Name)
+		}
+	}
+}
+
+func pixelOffset(x, y, rowstride, channels int) int {
+	return y*rowstride + x*channels
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func grayscale(pixels []byte, width, height, rowstride, channels int) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			off := pixelOffset(x, y, rowstride, channels)
+			r, g, b := pixels[off], pixels[off+1], pixels[off+2]
+			gray := byte(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b))
+			pixels[off], pixels[off+1], pixels[off+2] = gray, gray, gray
+		}
+	}
+}
+
+// gaussianBlur applies a separable box-approximated Gaussian blur of the
+// given radius.
+func gaussianBlur(pixels []byte, width, height, rowstride, channels, radius int) {
+	src := make([]byte, len(pixels))
+	copy(src, pixels)
+
+	// Horizontal pass.
+	tmp := make([]byte, len(pixels))
+	copy(tmp, src)
+	blurPass(src, tmp, width, height, rowstride, channels, radius, true)
+
+	// Vertical pass.
+	blurPass(tmp, pixels, width, height, rowstride, channels, radius, false)
+}
+
+func blurPass(src, dst []byte, width, height, rowstride, channels, radius int, horizontal bool) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sums [4]int
+			count := 0
+
+			for d := -radius; d <= radius; d++ {
+				sx, sy := x, y
+				if horizontal {
+					sx = x + d
+				} else {
+					sy = y + d
+				}
+				if sx < 0 || sx >= width || sy < 0 || sy >= height {
+					continue
+				}
+
+				off := pixelOffset(sx, sy, rowstride, channels)
+				for c := 0; c < channels; c++ {
+					sums[c] += int(src[off+c])
+				}
+				count++
+			}
+
+			off := pixelOffset(x, y, rowstride, channels)
+			for c := 0; c < channels; c++ {
+				dst[off+c] = byte(sums[c] / count)
+			}
+		}
+	}
+}
+
+var sobelGx = [3][3]int{
+	{-1, 0, 1},
+	{-2, 0, 2},
+	{-1, 0, 1},
+}
+
+var sobelGy = [3][3]int{
+	{-1, -2, -1},
+	{0, 0, 0},
+	{1, 2, 1},
+}
+
+// edgeDetectSobel runs the standard two-pass Sobel operator (Gx and Gy)
+// over the grayscale luminance of the image and writes
+// sqrt(gx^2+gy^2), clamped to 255, back into all three color channels.
+func edgeDetectSobel(pixels []byte, width, height, rowstride, channels int) {
+	lum := make([]byte, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			off := pixelOffset(x, y, rowstride, channels)
+			r, g, b := pixels[off], pixels[off+1], pixels[off+2]
+			lum[y*width+x] = byte(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b))
+		}
+	}
+
+	out := make([]byte, len(pixels))
+	copy(out, pixels)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var gx, gy int
+			for ky := -1; ky <= 1; ky++ {
+				for kx := -1; kx <= 1; kx++ {
+					sx := clampInt(x+kx, 0, width-1)
+					sy := clampInt(y+ky, 0, height-1)
+					v := int(lum[sy*width+sx])
+					gx += sobelGx[ky+1][kx+1] * v
+					gy += sobelGy[ky+1][kx+1] * v
+				}
+			}
+
+			mag := math.Sqrt(float64(gx*gx + gy*gy))
+			if mag > 255 {
+				mag = 255
+			}
+
+			off := pixelOffset(x, y, rowstride, channels)
+			out[off] = byte(mag)
+			out[off+1] = byte(mag)
+			out[off+2] = byte(mag)
+		}
+	}
+
+	copy(pixels, out)
+}
+
+// bayer4x4 is the standard 4x4 ordered-dither threshold matrix, scaled to
+// [0, 1); orderedDither subtracts 0.5 from each value to center it on zero.
+var bayer4x4 = [4][4]float64{
+	{0.0 / 16, 8.0 / 16, 2.0 / 16, 10.0 / 16},
+	{12.0 / 16, 4.0 / 16, 14.0 / 16, 6.0 / 16},
+	{3.0 / 16, 11.0 / 16, 1.0 / 16, 9.0 / 16},
+	{15.0 / 16, 7.0 / 16, 13.0 / 16, 5.0 / 16},
+}
+
+// orderedDither quantizes each channel to paletteSize evenly spaced levels
+// using a 4x4 Bayer dither matrix, useful for e-ink-style displays.
+func orderedDither(pixels []byte, width, height, rowstride, channels, paletteSize int) {
+	const spread = 64.0
+	levels := float64(paletteSize - 1)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			threshold := bayer4x4[y%4][x%4] - 0.5
+
+			off := pixelOffset(x, y, rowstride, channels)
+			for c := 0; c < 3 && c < channels; c++ {
+				v := float64(pixels[off+c]) + threshold*spread
+				quantized := math.Round(v/255.0*levels) / levels * 255.0
+				if quantized < 0 {
+					quantized = 0
+				} else if quantized > 255 {
+					quantized = 255
+				}
+				pixels[off+c] = byte(quantized)
+			}
+		}
+	}
+}
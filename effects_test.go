@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+// rgbaBuffer builds a tightly-packed RGBA buffer (rowstride == width*4) of
+// width x height pixels, all set to the given color, for exercising the
+// pure pixel-math effects without a real gdk.Pixbuf.
+func rgbaBuffer(width, height int, r, g, b, a byte) []byte {
+	buf := make([]byte, width*height*4)
+	for i := 0; i < len(buf); i += 4 {
+		buf[i], buf[i+1], buf[i+2], buf[i+3] = r, g, b, a
+	}
+	return buf
+}
+
+func TestGrayscale(t *testing.T) {
+	const w, h = 2, 2
+	pixels := rgbaBuffer(w, h, 10, 20, 30, 255)
+
+	grayscale(pixels, w, h, w*4, 4)
+
+	r, g, b := 10.0, 20.0, 30.0
+	want := byte(0.299*r + 0.587*g + 0.114*b)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			off := pixelOffset(x, y, w*4, 4)
+			if pixels[off] != want || pixels[off+1] != want || pixels[off+2] != want {
+				t.Fatalf("pixel (%d,%d) = %v, want gray %d in all channels", x, y, pixels[off:off+3], want)
+			}
+		}
+	}
+}
+
+func TestEdgeDetectSobelFlatImageHasNoEdges(t *testing.T) {
+	const w, h = 8, 8
+	pixels := rgbaBuffer(w, h, 128, 128, 128, 255)
+
+	edgeDetectSobel(pixels, w, h, w*4, 4)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			off := pixelOffset(x, y, w*4, 4)
+			if pixels[off] != 0 {
+				t.Fatalf("pixel (%d,%d) = %d, want 0 on a flat image", x, y, pixels[off])
+			}
+		}
+	}
+}
+
+func TestOrderedDitherQuantizesToPalette(t *testing.T) {
+	const w, h = 4, 4
+	const paletteSize = 2
+	pixels := rgbaBuffer(w, h, 200, 200, 200, 255)
+
+	orderedDither(pixels, w, h, w*4, 4, paletteSize)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			off := pixelOffset(x, y, w*4, 4)
+			v := pixels[off]
+			if v != 0 && v != 255 {
+				t.Fatalf("pixel (%d,%d) channel = %d, want a palette level (0 or 255) for paletteSize=2", x, y, v)
+			}
+		}
+	}
+}
+
+func TestBlurPassAveragesNeighbors(t *testing.T) {
+	const w, h = 5, 1
+	pixels := []byte{0, 0, 0, 255, 0, 0, 0, 255, 255, 0, 0, 255, 0, 0, 0, 255, 0, 0, 0, 255}
+	dst := make([]byte, len(pixels))
+
+	blurPass(pixels, dst, w, h, w*4, 4, 1, true)
+
+	// The center pixel (index 2) averages itself with its two neighbors:
+	// (0+255+0)/3 = 85.
+	off := pixelOffset(2, 0, w*4, 4)
+	if dst[off] != 85 {
+		t.Fatalf("center red channel = %d, want 85", dst[off])
+	}
+}
+
+func TestEffectsSignatureIncludesBlurAlias(t *testing.T) {
+	a := effectsSignature([]EffectSpec{{Name: "blur", Param: 3}})
+	b := effectsSignature([]EffectSpec{{Name: "gaussian_blur", Param: 3}})
+	if a == b {
+		t.Fatalf("blur and gaussian_blur produced the same cache signature %q; distinct effect names must not collide", a)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gotk3/gotk3/glib"
+)
+
+// isRemotePath reports whether path is an http(s) URL rather than a local
+// filesystem path.
+func isRemotePath(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// fetchToCache downloads rawURL into cacheDir, re-using the cached copy
+// when the server reports it hasn't changed (ETag / Last-Modified). It
+// returns the local path to use in place of rawURL.
+func fetchToCache(rawURL, cacheDir string) (string, error) {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", err
+	}
+
+	localPath := filepath.Join(cacheDir, url.QueryEscape(rawURL))
+	metaPath := localPath + ".meta"
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if meta, err := os.ReadFile(metaPath); err == nil {
+		lines := strings.SplitN(string(meta), "\n", 2)
+		if len(lines) == 2 {
+			if lines[0] != "" {
+				req.Header.Set("If-None-Match", lines[0])
+			}
+			if lines[1] != "" {
+				req.Header.Set("If-Modified-Since", lines[1])
+			}
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return localPath, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: unexpected status %s", rawURL, resp.Status)
+	}
+
+	out, err := os.Create(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", err
+	}
+
+	meta := resp.Header.Get("ETag") + "\n" + resp.Header.Get("Last-Modified")
+	_ = os.WriteFile(metaPath, []byte(meta), 0o644)
+
+	return localPath, nil
+}
+
+// remoteCacheDir is where downloaded manifests, content-directory listings,
+// and per-entry images are cached.
+const remoteCacheDir = "./.comic-flex-cache"
+
+// resolveManifestPath returns a local path usable by a ManifestSource,
+// downloading path first if it's an http(s) URL.
+func resolveManifestPath(path string) (string, error) {
+	if !isRemotePath(path) {
+		return path, nil
+	}
+	return fetchToCache(path, remoteCacheDir)
+}
+
+// remoteContentIndex is the expected shape of a content_directory URL's
+// response: a flat list of image URLs to mirror locally.
+type remoteContentIndex struct {
+	Images []string `json:"images"`
+}
+
+// resolveContentDirectory mirrors a remote content_directory (a URL
+// returning a remoteContentIndex JSON document) into the local cache and
+// returns the local directory to pass to listImages. Local paths are
+// returned unchanged.
+func resolveContentDirectory(path string) (string, error) {
+	if !isRemotePath(path) {
+		return path, nil
+	}
+
+	indexPath, err := fetchToCache(path, remoteCacheDir)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return "", err
+	}
+
+	var index remoteContentIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(remoteCacheDir, "content")
+	for _, imageURL := range index.Images {
+		if _, err := fetchToCache(imageURL, dir); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// resolveImagePath downloads an individual entry's image_path if it's a
+// URL, returning the local path to decode instead.
+func resolveImagePath(path string) (string, error) {
+	if !isRemotePath(path) {
+		return path, nil
+	}
+	return fetchToCache(path, filepath.Join(remoteCacheDir, "content"))
+}
+
+// resolveManifestEntryImages rewrites each entry's ImagePath in place via
+// resolveImagePath, so a manifest loaded with http(s):// image_path values
+// ends up pointing at local files before it's handed to callers. Used both
+// at startup and whenever a manifest is reloaded.
+func resolveManifestEntryImages(manifest *Manifest) {
+	for i, entry := range manifest.Entries {
+		localImagePath, err := resolveImagePath(entry.ImagePath)
+		if err != nil {
+			log.Printf("Failed to fetch remote entry image %s: %v", entry.ImagePath, err)
+			continue
+		}
+		manifest.Entries[i].ImagePath = localImagePath
+	}
+}
+
+// controlServer exposes the slideshow's remote control HTTP API. All
+// handlers marshal their effect back onto the GTK main loop via
+// glib.IdleAdd rather than touching GTK state from the HTTP goroutine.
+type controlServer struct {
+	token string
+
+	state  func() map[string]interface{}
+	next   func()
+	prev   func()
+	goTo   func(id string) bool
+	pause  func()
+	resume func()
+	reload func()
+}
+
+func newControlServer(listen, token string, cs controlServer) (*http.Server, error) {
+	cs.token = token
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", cs.handleState)
+	mux.HandleFunc("/next", cs.handleAction(cs.next))
+	mux.HandleFunc("/prev", cs.handleAction(cs.prev))
+	mux.HandleFunc("/pause", cs.handleAction(cs.pause))
+	mux.HandleFunc("/resume", cs.handleAction(cs.resume))
+	mux.HandleFunc("/reload", cs.handleAction(cs.reload))
+	mux.HandleFunc("/goto/", cs.handleGoto)
+
+	server := &http.Server{Addr: listen, Handler: cs.authenticate(mux)}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("http control server stopped: %v\n", err)
+		}
+	}()
+
+	return server, nil
+}
+
+func (cs *controlServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cs.token != "" && r.Header.Get("Authorization") != "Bearer "+cs.token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// controlDispatchTimeout bounds how long an HTTP handler waits for its
+// glib.IdleAdd callback to run on the GTK main loop. Without it, a request
+// arriving after the window has been destroyed (main loop gone) would hang
+// the HTTP client and leak the waiting goroutine forever.
+const controlDispatchTimeout = 3 * time.Second
+
+// dispatch runs fn on the GTK main loop via glib.IdleAdd and waits for it
+// to finish, returning its result. It returns ok=false if fn doesn't run
+// within controlDispatchTimeout.
+func (cs *controlServer) dispatch(fn func() interface{}) (result interface{}, ok bool) {
+	done := make(chan interface{}, 1)
+	glib.IdleAdd(func() bool {
+		done <- fn()
+		return false
+	})
+
+	select {
+	case result := <-done:
+		return result, true
+	case <-time.After(controlDispatchTimeout):
+		return nil, false
+	}
+}
+
+func (cs *controlServer) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, ok := cs.dispatch(func() interface{} { return cs.state() })
+	if !ok {
+		http.Error(w, "request timed out", http.StatusGatewayTimeout)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (cs *controlServer) handleAction(action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, ok := cs.dispatch(func() interface{} { action(); return nil }); !ok {
+			http.Error(w, "request timed out", http.StatusGatewayTimeout)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (cs *controlServer) handleGoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/goto/")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	result, ok := cs.dispatch(func() interface{} { return cs.goTo(id) })
+	if !ok {
+		http.Error(w, "request timed out", http.StatusGatewayTimeout)
+		return
+	}
+
+	if !result.(bool) {
+		http.Error(w, "unknown id", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -18,10 +20,11 @@ import (
 )
 
 type Entry struct {
-	ID          string `yaml:"id"`
-	Title       string `yaml:"title"`
-	ImagePath   string `yaml:"image_path"`
-	Description string `yaml:"short_description"`
+	ID          string       `yaml:"id"`
+	Title       string       `yaml:"title"`
+	ImagePath   string       `yaml:"image_path"`
+	Description string       `yaml:"short_description"`
+	Effects     []EffectSpec `yaml:"effects"`
 }
 
 type Manifest struct {
@@ -29,13 +32,27 @@ type Manifest struct {
 }
 
 type Config struct {
-	ContentDirectory string `yaml:"content_directory"`
-	ManifestPath     string `yaml:"manifest_path"`
-	SlideInterval    uint   `yaml:"slide_interval"`
-	FillColor        string `yaml:"fill_color"`
-	TextColor        string `yaml:"text_color"`
-	EnableText       bool   `yaml:"enable_text"`
-	IsRandomOrder    bool   `yaml:"is_random_order"`
+	ContentDirectory string       `yaml:"content_directory"`
+	ManifestPath     string       `yaml:"manifest_path"`
+	SlideInterval    uint         `yaml:"slide_interval"`
+	FillColor        string       `yaml:"fill_color"`
+	TextColor        string       `yaml:"text_color"`
+	EnableText       bool         `yaml:"enable_text"`
+	IsRandomOrder    bool         `yaml:"is_random_order"`
+	CacheSizeMB      int          `yaml:"cache_size_mb"`
+	CaptionPosition  string       `yaml:"caption_position"`
+	CaptionFont      string       `yaml:"caption_font"`
+	CaptionFontSize  int          `yaml:"caption_font_size"`
+	CaptionBorder    int          `yaml:"caption_border"`
+	CaptionBGAlpha   *float64     `yaml:"caption_bg_alpha"`
+	CaptionMaxLines  int          `yaml:"caption_max_lines"`
+	Transition       string       `yaml:"transition"`
+	TransitionMs     uint         `yaml:"transition_ms"`
+	ManifestType     string       `yaml:"manifest_type"`
+	EnableHotReload  bool         `yaml:"enable_hot_reload"`
+	HTTPListen       string       `yaml:"http_listen"`
+	HTTPToken        string       `yaml:"http_token"`
+	DefaultEffects   []EffectSpec `yaml:"default_effects"`
 }
 
 func loadManifest(path string) (*Manifest, error) {
@@ -135,6 +152,14 @@ func listImages(path string, isRandomOrder bool) ([]string, error) {
 	return images, nil
 }
 
+func fileMTime(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
 func hexToRGB(hexColor string) (float64, float64, float64, error) {
 	var r, g, b uint8
 	_, err := fmt.Sscanf(hexColor, "#%02x%02x%02x", &r, &g, &b)
@@ -155,6 +180,11 @@ func main() {
 		contentDirectory = "./content"
 	}
 
+	contentDirectory, err = resolveContentDirectory(contentDirectory)
+	if err != nil {
+		log.Fatalf("Failed to fetch remote content directory: %v", err)
+	}
+
 	slideInterval := config.SlideInterval * 1000
 	if slideInterval == 0 {
 		slideInterval = 30000
@@ -172,6 +202,41 @@ func main() {
 		textColor = "#000000"
 	}
 
+	captionPosition := config.CaptionPosition
+	if captionPosition == "" {
+		captionPosition = "bottom"
+	}
+
+	captionFont := config.CaptionFont
+	if captionFont == "" {
+		captionFont = "Sans"
+	}
+
+	captionFontSize := config.CaptionFontSize
+	if captionFontSize == 0 {
+		captionFontSize = 24
+	}
+
+	captionBorder := float64(config.CaptionBorder)
+
+	// A nil CaptionBGAlpha means the key was absent from the YAML and
+	// defaults to fully opaque; an explicit caption_bg_alpha: 0.0 is a
+	// valid fully-transparent card and must be honored as-is.
+	captionBGAlpha := 1.0
+	if config.CaptionBGAlpha != nil {
+		captionBGAlpha = *config.CaptionBGAlpha
+	}
+
+	transitionMode := config.Transition
+	if transitionMode == "" {
+		transitionMode = "none"
+	}
+
+	transitionMs := config.TransitionMs
+	if transitionMs == 0 {
+		transitionMs = 500
+	}
+
 	fillColorR, fillColorG, fillColorB, err := hexToRGB(fillColor)
 	if err != nil {
 		log.Fatal(err)
@@ -182,11 +247,19 @@ func main() {
 		manifestPath = "./manifest.yaml"
 	}
 
-	manifest, err := loadManifest(manifestPath)
+	manifestPath, err = resolveManifestPath(manifestPath)
+	if err != nil {
+		log.Fatalf("Failed to fetch remote manifest: %v", err)
+	}
+
+	manifestSource := selectManifestSource(config.ManifestType, manifestPath)
+	manifest, err := manifestSource.Load(manifestPath)
 	if err != nil {
 		log.Fatalf("Failed to load manifest: %v", err)
 	}
 
+	resolveManifestEntryImages(manifest)
+
 	gtk.Init(nil)
 
 	win, err := gtk.WindowNew(gtk.WINDOW_TOPLEVEL)
@@ -213,7 +286,12 @@ func main() {
 
 	gtk.AddProviderForScreen(screen, cssProvider, uint(gtk.STYLE_PROVIDER_PRIORITY_USER))
 
+	var controlHTTPServer *http.Server
+
 	win.Connect("destroy", func() {
+		if controlHTTPServer != nil {
+			controlHTTPServer.Close()
+		}
 		gtk.MainQuit()
 	})
 	win.Fullscreen()
@@ -240,11 +318,13 @@ func main() {
 		log.Fatal("Unable to create overlay:", err)
 	}
 
-	img, err := gtk.ImageNew()
+	slideArea, err := gtk.DrawingAreaNew()
 	if err != nil {
-		log.Fatal("Unable to create image:", err)
+		log.Fatal("Unable to create slide drawing area:", err)
 	}
-	overlay.Add(img)
+	overlay.Add(slideArea)
+
+	transitions := newTransitionEngine(slideArea, transitionMode, transitionMs)
 
 	drawingArea, err := gtk.DrawingAreaNew()
 	if err != nil {
@@ -252,13 +332,30 @@ func main() {
 	}
 	drawingArea.SetSizeRequest(800, 100) // Set the size as per your requirement
 
-	textCardHeight := 150.0
+	// captionCardSize is the strip thickness captionCardRect draws (height
+	// for top/bottom, width for left/right): captionBorder padding on both
+	// sides of a title line plus up to CaptionMaxLines description lines,
+	// each sized off captionFontSize rather than a fixed 150px guess.
+	descLines := config.CaptionMaxLines
+	if descLines <= 0 {
+		descLines = 3
+	}
+	titleLineHeight := float64(captionFontSize) * 1.4
+	descLineHeight := float64(captionFontSize-4) * 1.4
+	captionCardSize := 2*captionBorder + titleLineHeight + descLineHeight*float64(descLines)
 
-	// Draw event for drawing background
+	// Draw event for drawing the caption card background. The card's
+	// rectangle and translucency follow the caption_position/caption_bg_alpha
+	// config, so this is not hard-coded to a bottom strip any more.
 	drawingArea.Connect("draw", func(da *gtk.DrawingArea, cr *cairo.Context) {
-		// Set the color for your background
-		cr.SetSourceRGB(fillColorR, fillColorG, fillColorB)
-		cr.Rectangle(0, float64(da.GetAllocatedHeight())-textCardHeight, float64(da.GetAllocatedWidth()), textCardHeight)
+		if captionPosition == "none" {
+			return
+		}
+
+		rect := captionCardRect(float64(da.GetAllocatedWidth()), float64(da.GetAllocatedHeight()), captionCardSize, captionPosition)
+
+		cr.SetSourceRGBA(fillColorR, fillColorG, fillColorB, captionBGAlpha)
+		cr.Rectangle(rect.X, rect.Y, rect.W, rect.H)
 		cr.Fill()
 	})
 	overlay.AddOverlay(drawingArea)
@@ -267,101 +364,238 @@ func main() {
 	if err != nil {
 		log.Fatal("Unable to create text container:", err)
 	}
-	textContainer.SetVAlign(gtk.ALIGN_END) // Align at the bottom
+
+	textHAlign, textVAlign := captionAlign(captionPosition)
+	textContainer.SetHAlign(textHAlign)
+	textContainer.SetVAlign(textVAlign)
 
 	overlay.AddOverlay(textContainer)
 
-	textContainer.PackStart(titleLabel, false, false, 10)
-	textContainer.PackStart(descLabel, false, false, 10)
+	textContainer.PackStart(titleLabel, false, false, uint(captionBorder))
+	textContainer.PackStart(descLabel, false, false, uint(captionBorder))
 
 	win.Add(overlay)
 
-	images, err := listImages(contentDirectory, config.IsRandomOrder)
+	images, err := imagesForManifest(manifestSource, manifest, contentDirectory, config.IsRandomOrder)
 	if err != nil {
 		log.Fatalf("Failed to list images: %v", err)
 	}
 
 	currentIndex := 0
 
-	// Function to update the image and reset timer
-	var updateImage func() func()
-	updateImage = func() func() {
-		if currentIndex < 0 || currentIndex >= len(images) {
-			currentIndex = 0
+	thumbs := newThumbCache(config.CacheSizeMB)
+	const prefetchWindow = 2
+
+	var pendingCancel context.CancelFunc
+
+	applyCaption := func(imagePath string) {
+		if !enableText || captionPosition == "none" {
+			return
 		}
 
-		imagePath := images[currentIndex]
+		titleLabel.SetMarkup("")
+		descLabel.SetMarkup("")
+		overlay.Remove(drawingArea)
+		overlay.Remove(textContainer)
 
-		fmt.Printf("%+v\n", imagePath)
-		pixbuf, err := gdk.PixbufNewFromFile(imagePath)
-		if err != nil {
-			fmt.Printf("Unable to create pixbuf: %+v", err)
-			return func() {
-				gdk.Pixbuf.Unref(*pixbuf)
+		for _, entry := range manifest.Entries {
+			if entry.ImagePath == imagePath {
+				description := truncateCaption(entry.Description, config.CaptionMaxLines)
+
+				titleLabel.SetMarkup(fmt.Sprintf("<span foreground=\"%s\" font=\"%s %d\">%s</span>", textColor, captionFont, captionFontSize, entry.Title))
+				descLabel.SetMarkup(fmt.Sprintf("<span foreground=\"%s\" font=\"%s %d\">%s</span>", textColor, captionFont, captionFontSize-4, description))
+				overlay.AddOverlay(drawingArea)
+				overlay.AddOverlay(textContainer)
+				break
 			}
 		}
+	}
+
+	// destSizeFor returns the scaled dimensions that preserve the image's
+	// aspect ratio within the current window, shrunk along whichever axis
+	// the caption card actually occupies for captionPosition.
+	destSizeFor := func(origWidth, origHeight int) (int, int) {
+		width, height := win.GetSize()
+		switch captionPosition {
+		case "left", "right":
+			width = width - int(captionCardSize)
+		case "none":
+			// no card drawn, nothing to reserve
+		default: // "top", "bottom"
+			height = height - int(captionCardSize)
+		}
+
+		scale := math.Min(float64(width)/float64(origWidth), float64(height)/float64(origHeight))
+		return int(float64(origWidth) * scale), int(float64(origHeight) * scale)
+	}
 
-		if pixbuf == nil {
-			fmt.Println("Pixbuf is nil")
-			return func() {
+	// effectsFor returns the per-entry effects pipeline for imagePath, or
+	// Config.DefaultEffects if the manifest entry declares none.
+	effectsFor := func(imagePath string) []EffectSpec {
+		for _, entry := range manifest.Entries {
+			if entry.ImagePath == imagePath && len(entry.Effects) > 0 {
+				return entry.Effects
 			}
 		}
+		return config.DefaultEffects
+	}
 
-		// Calculate the scale preserving aspect ratio
-		origWidth := pixbuf.GetWidth()
-		origHeight := pixbuf.GetHeight()
+	prefetchAround := func(index int) {
+		for offset := -prefetchWindow; offset <= prefetchWindow; offset++ {
+			if offset == 0 {
+				continue
+			}
+			idx := ((index+offset)%len(images) + len(images)) % len(images)
+			path := images[idx]
 
-		if origWidth == 0 || origHeight == 0 {
-			fmt.Println("Pixbuf width or height is 0")
-			return func() {
-				gdk.Pixbuf.Unref(*pixbuf)
+			_, origWidth, origHeight, err := gdk.PixbufGetFileInfo(path)
+			if err != nil || origWidth == 0 || origHeight == 0 {
+				continue
 			}
+
+			destWidth, destHeight := destSizeFor(origWidth, origHeight)
+			thumbs.prefetch(path, fileMTime(path), destWidth, destHeight, effectsFor(path))
 		}
+	}
 
-		// Get window size
-		width, height := win.GetSize()
-		height = height - int(textCardHeight)
+	// Function to update the image and reset timer. Cancels any in-flight
+	// decode for the slide it is replacing so we don't burn CPU on images
+	// the user has already skipped past.
+	var updateImage func()
+	updateImage = func() {
+		if currentIndex < 0 || currentIndex >= len(images) {
+			currentIndex = 0
+		}
 
-		scale := math.Min(float64(width)/float64(origWidth), float64(height)/float64(origHeight))
+		if pendingCancel != nil {
+			pendingCancel()
+		}
 
-		// Scale the image
-		destWidth := int(float64(origWidth) * scale)
-		destHeight := int(float64(origHeight) * scale)
-		fmt.Printf("%+v %v %v\n", pixbuf, destWidth, destHeight)
-		scaledPixbuf, err := pixbuf.ScaleSimple(destWidth, destHeight, gdk.INTERP_BILINEAR)
-		if err != nil {
-			log.Fatal("Unable to scale pixbuf:", err)
-			return func() {
-				gdk.Pixbuf.Unref(*pixbuf)
-				gdk.Pixbuf.Unref(*scaledPixbuf)
+		imagePath := images[currentIndex]
+
+		_, origWidth, origHeight, err := gdk.PixbufGetFileInfo(imagePath)
+		if err != nil || origWidth == 0 || origHeight == 0 {
+			fmt.Printf("Unable to read image info for %s: %+v\n", imagePath, err)
+			return
+		}
+
+		destWidth, destHeight := destSizeFor(origWidth, origHeight)
+
+		requestedIndex := currentIndex
+		pendingCancel = thumbs.request(imagePath, fileMTime(imagePath), destWidth, destHeight, effectsFor(imagePath), func(pixbuf *gdk.Pixbuf) {
+			if requestedIndex != currentIndex {
+				return
 			}
+
+			transitions.goTo(pixbuf)
+
+			applyCaption(imagePath)
+		})
+
+		prefetchAround(currentIndex)
+	}
+
+	if config.EnableHotReload {
+		_, err := newReloadWatcher(manifestPath, contentDirectory, manifestSource, manifest, config.IsRandomOrder,
+			func(reloaded *Manifest) {
+				manifest.Entries = reloaded.Entries
+			},
+			func(reloaded []string) {
+				currentPath := ""
+				if currentIndex >= 0 && currentIndex < len(images) {
+					currentPath = images[currentIndex]
+				}
+
+				images = reloaded
+
+				currentIndex = 0
+				for i, path := range images {
+					if path == currentPath {
+						currentIndex = i
+						break
+					}
+				}
+
+				updateImage()
+			},
+		)
+		if err != nil {
+			log.Printf("Failed to start hot reload watcher: %v", err)
 		}
+	}
 
-		img.Clear()
-		img.SetFromPixbuf(scaledPixbuf)
+	paused := false
 
-		img.SetVAlign(gtk.ALIGN_START)
+	goNext := func() {
+		currentIndex = (currentIndex + 1) % len(images)
+		updateImage()
+	}
 
-		if enableText {
-			titleLabel.SetMarkup("")
-			descLabel.SetMarkup("")
-			overlay.Remove(drawingArea)
-			overlay.Remove(textContainer)
+	goPrev := func() {
+		// Ensuring currentIndex doesn't go below 0
+		if currentIndex == 0 {
+			currentIndex = len(images) - 1
+		} else {
+			currentIndex--
+		}
+		updateImage()
+	}
 
-			for _, entry := range manifest.Entries {
-				if entry.ImagePath == imagePath {
-					titleLabel.SetMarkup("<span foreground=\"" + textColor + "\" font=\"24\">" + entry.Title + "</span>")
-					descLabel.SetMarkup("<span foreground=\"" + textColor + "\" font=\"20\">" + entry.Description + "</span>")
-					overlay.AddOverlay(drawingArea)
-					overlay.AddOverlay(textContainer)
-					break
+	goToID := func(id string) bool {
+		for _, entry := range manifest.Entries {
+			if entry.ID != id {
+				continue
+			}
+			for i, path := range images {
+				if path == entry.ImagePath {
+					currentIndex = i
+					updateImage()
+					return true
 				}
 			}
 		}
+		return false
+	}
 
-		return func() {
-			gdk.Pixbuf.Unref(*pixbuf)
-			gdk.Pixbuf.Unref(*scaledPixbuf)
+	if config.HTTPListen != "" {
+		controlHTTPServer, err = newControlServer(config.HTTPListen, config.HTTPToken, controlServer{
+			state: func() map[string]interface{} {
+				path := ""
+				if currentIndex >= 0 && currentIndex < len(images) {
+					path = images[currentIndex]
+				}
+				return map[string]interface{}{
+					"index":  currentIndex,
+					"total":  len(images),
+					"path":   path,
+					"paused": paused,
+				}
+			},
+			next:   goNext,
+			prev:   goPrev,
+			goTo:   goToID,
+			pause:  func() { paused = true },
+			resume: func() { paused = false },
+			reload: func() {
+				// listImages walks the whole content tree, so it must not
+				// run on the GTK main thread; only the lightweight result
+				// is marshaled back via glib.IdleAdd.
+				go func() {
+					reloaded, err := imagesForManifest(manifestSource, manifest, contentDirectory, config.IsRandomOrder)
+					if err != nil {
+						log.Printf("Failed to reload images: %v", err)
+						return
+					}
+					glib.IdleAdd(func() bool {
+						images = reloaded
+						updateImage()
+						return false
+					})
+				}()
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to start HTTP control server: %v", err)
 		}
 	}
 
@@ -372,10 +606,10 @@ func main() {
 		glib.SourceRemove(timeoutID)
 	}
 	timeoutID = glib.TimeoutAdd(slideInterval, func() bool {
-		currentIndex = (currentIndex + 1) % len(images)
-		cleanup := updateImage()
-		cleanup()
-		return false // Stop the current timeout
+		if !paused {
+			goNext()
+		}
+		return true // keep advancing until the window is destroyed
 	})
 
 	// Key press event handler
@@ -383,30 +617,19 @@ func main() {
 		keyEvent := &gdk.EventKey{Event: event}
 		switch keyEvent.KeyVal() {
 		case gdk.KEY_space, gdk.KEY_Right:
-			currentIndex = (currentIndex + 1) % len(images)
+			goNext()
 		case gdk.KEY_Left:
-			// Ensuring currentIndex doesn't go below 0
-			if currentIndex == 0 {
-				currentIndex = len(images) - 1
-			} else {
-				currentIndex--
-			}
+			goPrev()
 		}
-
-		cleanup := updateImage()
-		cleanup()
 	})
 
 	// Mouse click event handler
 	win.Connect("button-press-event", func(win *gtk.Window, event *gdk.Event) {
-		currentIndex = (currentIndex + 1) % len(images)
-		cleanup := updateImage()
-		cleanup()
+		goNext()
 	})
 
 	// Initial image update
-	cleanup := updateImage()
-	cleanup()
+	updateImage()
 
 	win.ShowAll()
 	gtk.Main()
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestSource loads a Manifest from a path, whatever format that path
+// happens to be in. YAMLSource reads the hand-authored manifest.yaml format;
+// DesktopSource reads freedesktop .desktop files.
+type ManifestSource interface {
+	Load(path string) (*Manifest, error)
+}
+
+// YAMLSource loads the existing manifest.yaml format via loadManifest.
+type YAMLSource struct{}
+
+func (YAMLSource) Load(path string) (*Manifest, error) {
+	return loadManifest(path)
+}
+
+// DesktopSource turns a .desktop file, or a directory of them, into a
+// Manifest: one Entry per file, using Name= as Title, Comment= as
+// Description, Icon= resolved via the icon-theme search path as ImagePath,
+// and the file's basename (without extension) as ID.
+type DesktopSource struct{}
+
+func (DesktopSource) Load(path string) (*Manifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var desktopFiles []string
+
+	if info.IsDir() {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && filepath.Ext(p) == ".desktop" {
+				desktopFiles = append(desktopFiles, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		desktopFiles = []string{path}
+	}
+
+	var manifest Manifest
+
+	for _, file := range desktopFiles {
+		entry, err := parseDesktopEntry(file)
+		if err != nil {
+			continue
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+	}
+
+	return &manifest, nil
+}
+
+func parseDesktopEntry(path string) (Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer f.Close()
+
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	entry := Entry{ID: id}
+
+	inDesktopEntrySection := false
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			inDesktopEntrySection = line == "[Desktop Entry]"
+			continue
+		}
+
+		if !inDesktopEntrySection {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "Name":
+			entry.Title = strings.TrimSpace(value)
+		case "Comment":
+			entry.Description = strings.TrimSpace(value)
+		case "Icon":
+			entry.ImagePath = resolveIconPath(strings.TrimSpace(value))
+		}
+	}
+
+	return entry, scanner.Err()
+}
+
+// iconSearchDirs mirrors the standard freedesktop icon-theme search path.
+var iconSearchDirs = []string{
+	"/usr/share/icons/hicolor/256x256/apps",
+	"/usr/share/icons/hicolor/128x128/apps",
+	"/usr/share/icons/hicolor/scalable/apps",
+	"/usr/share/pixmaps",
+}
+
+// resolveIconPath resolves an Icon= value (an absolute path or a bare icon
+// name) to a concrete file on disk by walking the standard icon-theme
+// search path. An absolute path is returned unchanged without a search; if
+// a bare name is not found, the raw value is returned as-is.
+func resolveIconPath(icon string) string {
+	if filepath.IsAbs(icon) {
+		return icon
+	}
+
+	for _, dir := range iconSearchDirs {
+		for _, ext := range []string{".png", ".svg", ".xpm"} {
+			candidate := filepath.Join(dir, icon+ext)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+	}
+
+	return icon
+}
+
+// selectManifestSource picks a ManifestSource for path, preferring an
+// explicit manifestType ("yaml" or "desktop") and otherwise inferring it
+// from whether path points at a directory or a .desktop file.
+func selectManifestSource(manifestType, path string) ManifestSource {
+	switch manifestType {
+	case "desktop":
+		return DesktopSource{}
+	case "yaml":
+		return YAMLSource{}
+	}
+
+	if filepath.Ext(path) == ".desktop" {
+		return DesktopSource{}
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return DesktopSource{}
+	}
+
+	return YAMLSource{}
+}
+
+// imagesForManifest returns the slideshow's image paths for the given
+// manifest source. A DesktopSource has no unrelated content directory to
+// walk: the image list *is* the set of icon paths its entries already
+// resolved, so listImages (which only understands a directory of bare
+// image files and a fixed raster extension set) would miss them entirely.
+// Any other source falls back to the existing directory walk.
+func imagesForManifest(manifestSource ManifestSource, manifest *Manifest, contentDirectory string, isRandomOrder bool) ([]string, error) {
+	if _, ok := manifestSource.(DesktopSource); ok {
+		images := make([]string, 0, len(manifest.Entries))
+		for _, entry := range manifest.Entries {
+			if entry.ImagePath == "" {
+				continue
+			}
+			images = append(images, entry.ImagePath)
+		}
+
+		if len(images) == 0 {
+			return nil, fmt.Errorf("no manifest entries resolved an image_path (desktop entries need a resolvable Icon=)")
+		}
+
+		if isRandomOrder {
+			rdm := rand.New(rand.NewSource(time.Now().UnixNano()))
+			rdm.Shuffle(len(images), func(i, j int) {
+				images[i], images[j] = images[j], images[i]
+			})
+		}
+
+		return images, nil
+	}
+
+	images, err := listImages(contentDirectory, isRandomOrder)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images found under %q", contentDirectory)
+	}
+	return images, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeDesktopFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+	return path
+}
+
+func TestParseDesktopEntry(t *testing.T) {
+	dir := t.TempDir()
+	icon := writeDesktopFile(t, dir, "icon.png", "fake-png-bytes")
+	path := writeDesktopFile(t, dir, "comic.desktop", `[Desktop Entry]
+Name=My Comic
+Comment=A short blurb
+Icon=`+icon+`
+Type=Application
+`)
+
+	entry, err := parseDesktopEntry(path)
+	if err != nil {
+		t.Fatalf("parseDesktopEntry: %v", err)
+	}
+
+	if entry.ID != "comic" {
+		t.Errorf("ID = %q, want %q", entry.ID, "comic")
+	}
+	if entry.Title != "My Comic" {
+		t.Errorf("Title = %q, want %q", entry.Title, "My Comic")
+	}
+	if entry.Description != "A short blurb" {
+		t.Errorf("Description = %q, want %q", entry.Description, "A short blurb")
+	}
+	if entry.ImagePath != icon {
+		t.Errorf("ImagePath = %q, want %q", entry.ImagePath, icon)
+	}
+}
+
+func TestResolveIconPathAbsolutePassesThrough(t *testing.T) {
+	if got := resolveIconPath("/some/absolute/path.png"); got != "/some/absolute/path.png" {
+		t.Errorf("resolveIconPath(absolute) = %q, want unchanged", got)
+	}
+}
+
+func TestResolveIconPathFallsBackToRawValue(t *testing.T) {
+	// "definitely-not-a-real-icon" won't be found under any search dir, so
+	// the raw value should be returned unchanged rather than an empty string.
+	if got := resolveIconPath("definitely-not-a-real-icon"); got != "definitely-not-a-real-icon" {
+		t.Errorf("resolveIconPath(unresolvable) = %q, want the raw value back", got)
+	}
+}
+
+func TestDesktopSourceLoadKeepsUnresolvedIconName(t *testing.T) {
+	dir := t.TempDir()
+	writeDesktopFile(t, dir, "no-icon.desktop", `[Desktop Entry]
+Name=No Icon
+Icon=no-icon
+`)
+
+	manifest, err := (DesktopSource{}).Load(dir)
+	if err != nil {
+		t.Fatalf("DesktopSource.Load: %v", err)
+	}
+	if len(manifest.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(manifest.Entries))
+	}
+	if manifest.Entries[0].ImagePath != "no-icon" {
+		t.Fatalf("ImagePath = %q, want the unresolved raw icon name %q", manifest.Entries[0].ImagePath, "no-icon")
+	}
+}
+
+func TestImagesForManifestDesktopSourceUsesResolvedEntries(t *testing.T) {
+	manifest := &Manifest{Entries: []Entry{
+		{ID: "a", ImagePath: "/tmp/a.png"},
+		{ID: "b", ImagePath: ""}, // no Icon= key, must be skipped
+		{ID: "c", ImagePath: "/tmp/c.png"},
+	}}
+
+	images, err := imagesForManifest(DesktopSource{}, manifest, "/unused/content/dir", false)
+	if err != nil {
+		t.Fatalf("imagesForManifest: %v", err)
+	}
+	want := []string{"/tmp/a.png", "/tmp/c.png"}
+	if len(images) != len(want) {
+		t.Fatalf("images = %v, want %v", images, want)
+	}
+	for i, path := range want {
+		if images[i] != path {
+			t.Errorf("images[%d] = %q, want %q", i, images[i], path)
+		}
+	}
+}
+
+func TestImagesForManifestDesktopSourceErrorsWhenEmpty(t *testing.T) {
+	manifest := &Manifest{Entries: []Entry{{ID: "a", ImagePath: ""}}}
+
+	if _, err := imagesForManifest(DesktopSource{}, manifest, "/unused/content/dir", false); err == nil {
+		t.Fatal("imagesForManifest: want an error when no entry resolved an image, got nil")
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
+)
+
+// thumbKey identifies a decoded+scaled+effects-processed pixbuf in the
+// cache.
+type thumbKey struct {
+	path       string
+	mtime      int64
+	destWidth  int
+	destHeight int
+	effects    string
+}
+
+type thumbEntry struct {
+	key    thumbKey
+	pixbuf *gdk.Pixbuf
+	bytes  int64
+}
+
+// thumbCache decodes and scales pixbufs on a worker pool, keeping a bounded
+// LRU of the results keyed by (path, mtime, destWidth, destHeight, effects).
+// Callers
+// ask for a pixbuf via request() and get the result delivered back on the
+// GTK main thread via glib.IdleAdd.
+type thumbCache struct {
+	mu        sync.Mutex
+	lru       *list.List // front = most recently used, elements are *thumbEntry
+	index     map[thumbKey]*list.Element
+	sizeBytes int64
+	maxBytes  int64
+
+	jobs   chan thumbJob
+	wg     sync.WaitGroup
+	cancel map[thumbKey]inFlight
+}
+
+// inFlight tracks the context/cancel pair for a key's current in-flight job,
+// so a later request for the same key can cancel it and so a finished job
+// can remove its own entry without clobbering a newer one.
+type inFlight struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type thumbJob struct {
+	ctx      context.Context
+	key      thumbKey
+	effects  []EffectSpec
+	onReady  func(*gdk.Pixbuf)
+	priority bool
+}
+
+const thumbWorkerCount = 4
+
+func newThumbCache(cacheSizeMB int) *thumbCache {
+	if cacheSizeMB <= 0 {
+		cacheSizeMB = 256
+	}
+
+	tc := &thumbCache{
+		lru:      list.New(),
+		index:    make(map[thumbKey]*list.Element),
+		maxBytes: int64(cacheSizeMB) * 1024 * 1024,
+		jobs:     make(chan thumbJob, 64),
+		cancel:   make(map[thumbKey]inFlight),
+	}
+
+	for i := 0; i < thumbWorkerCount; i++ {
+		tc.wg.Add(1)
+		go tc.worker()
+	}
+
+	return tc
+}
+
+func (tc *thumbCache) worker() {
+	defer tc.wg.Done()
+
+	for job := range tc.jobs {
+		select {
+		case <-job.ctx.Done():
+			tc.clearCancel(job.key, job.ctx)
+			continue
+		default:
+		}
+
+		if pixbuf, ok := tc.get(job.key); ok {
+			tc.clearCancel(job.key, job.ctx)
+			glib.IdleAdd(func() bool {
+				job.onReady(pixbuf)
+				return false
+			})
+			continue
+		}
+
+		pixbuf, err := gdk.PixbufNewFromFile(job.key.path)
+		if err != nil {
+			tc.clearCancel(job.key, job.ctx)
+			continue
+		}
+
+		scaled, err := pixbuf.ScaleSimple(job.key.destWidth, job.key.destHeight, gdk.INTERP_BILINEAR)
+		gdk.Pixbuf.Unref(*pixbuf)
+		if err != nil {
+			tc.clearCancel(job.key, job.ctx)
+			continue
+		}
+
+		applyEffects(scaled, job.effects)
+
+		select {
+		case <-job.ctx.Done():
+			gdk.Pixbuf.Unref(*scaled)
+			tc.clearCancel(job.key, job.ctx)
+			continue
+		default:
+		}
+
+		tc.put(job.key, scaled)
+		tc.clearCancel(job.key, job.ctx)
+
+		glib.IdleAdd(func() bool {
+			job.onReady(scaled)
+			return false
+		})
+	}
+}
+
+// request schedules a decode for path, cancelling any previous in-flight
+// request for the same key. onReady is invoked on the GTK main thread once
+// the pixbuf is available, unless the returned cancel function is called
+// first.
+func (tc *thumbCache) request(path string, mtime int64, destWidth, destHeight int, effects []EffectSpec, onReady func(*gdk.Pixbuf)) context.CancelFunc {
+	key := thumbKey{path: path, mtime: mtime, destWidth: destWidth, destHeight: destHeight, effects: effectsSignature(effects)}
+
+	if pixbuf, ok := tc.get(key); ok {
+		glib.IdleAdd(func() bool {
+			onReady(pixbuf)
+			return false
+		})
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tc.mu.Lock()
+	if prev, ok := tc.cancel[key]; ok {
+		prev.cancel()
+	}
+	tc.cancel[key] = inFlight{ctx: ctx, cancel: cancel}
+	tc.mu.Unlock()
+
+	job := thumbJob{ctx: ctx, key: key, effects: effects, onReady: onReady}
+
+	select {
+	case tc.jobs <- job:
+	default:
+		go func() { tc.jobs <- job }()
+	}
+
+	return cancel
+}
+
+// clearCancel removes key's tc.cancel entry once ctx's job has finished, but
+// only if a newer request hasn't already replaced it with a different
+// context; otherwise it would delete that newer request's cancellation.
+func (tc *thumbCache) clearCancel(key thumbKey, ctx context.Context) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if current, ok := tc.cancel[key]; ok && current.ctx == ctx {
+		delete(tc.cancel, key)
+	}
+}
+
+// prefetch warms the cache for the given paths without delivering results
+// anywhere; it's used for the next/previous N images around currentIndex.
+func (tc *thumbCache) prefetch(path string, mtime int64, destWidth, destHeight int, effects []EffectSpec) {
+	tc.request(path, mtime, destWidth, destHeight, effects, func(*gdk.Pixbuf) {})
+}
+
+func (tc *thumbCache) get(key thumbKey) (*gdk.Pixbuf, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	elem, ok := tc.index[key]
+	if !ok {
+		return nil, false
+	}
+
+	tc.lru.MoveToFront(elem)
+	return elem.Value.(*thumbEntry).pixbuf, true
+}
+
+func (tc *thumbCache) put(key thumbKey, pixbuf *gdk.Pixbuf) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if elem, ok := tc.index[key]; ok {
+		tc.lru.MoveToFront(elem)
+		existing := elem.Value.(*thumbEntry)
+		gdk.Pixbuf.Unref(*existing.pixbuf)
+		existing.pixbuf = pixbuf
+		return
+	}
+
+	entryBytes := int64(pixbuf.GetRowstride()) * int64(pixbuf.GetHeight())
+	entry := &thumbEntry{key: key, pixbuf: pixbuf, bytes: entryBytes}
+	elem := tc.lru.PushFront(entry)
+	tc.index[key] = elem
+	tc.sizeBytes += entryBytes
+
+	for tc.sizeBytes > tc.maxBytes {
+		oldest := tc.lru.Back()
+		if oldest == nil {
+			break
+		}
+		oldEntry := oldest.Value.(*thumbEntry)
+		tc.lru.Remove(oldest)
+		delete(tc.index, oldEntry.key)
+		tc.sizeBytes -= oldEntry.bytes
+		gdk.Pixbuf.Unref(*oldEntry.pixbuf)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"container/list"
+	"testing"
+
+	"github.com/gotk3/gotk3/gdk"
+)
+
+func newTestPixbuf(t *testing.T, width, height int) *gdk.Pixbuf {
+	t.Helper()
+	pixbuf, err := gdk.PixbufNew(gdk.COLORSPACE_RGB, false, 8, width, height)
+	if err != nil {
+		t.Fatalf("gdk.PixbufNew: %v", err)
+	}
+	return pixbuf
+}
+
+// TestThumbCachePutEvictsPastCapacity exercises the LRU bookkeeping added in
+// this series: pushing entries past maxBytes must drop the oldest entries
+// from both the index and the running size total, keeping the cache's
+// memory footprint bounded rather than growing forever.
+func TestThumbCachePutEvictsPastCapacity(t *testing.T) {
+	const width, height = 4, 4 // rowstride * height bytes per entry
+
+	tc := &thumbCache{
+		lru:   list.New(),
+		index: make(map[thumbKey]*list.Element),
+	}
+
+	first := newTestPixbuf(t, width, height)
+	entryBytes := int64(first.GetRowstride()) * int64(first.GetHeight())
+	tc.maxBytes = entryBytes + entryBytes/2 // room for one entry, not two
+
+	keyA := thumbKey{path: "a.jpg", destWidth: width, destHeight: height}
+	keyB := thumbKey{path: "b.jpg", destWidth: width, destHeight: height}
+
+	tc.put(keyA, first)
+	tc.put(keyB, newTestPixbuf(t, width, height))
+
+	if tc.sizeBytes > tc.maxBytes {
+		t.Fatalf("sizeBytes = %d, want <= maxBytes %d after eviction", tc.sizeBytes, tc.maxBytes)
+	}
+
+	if _, ok := tc.get(keyA); ok {
+		t.Error("keyA should have been evicted to stay within maxBytes, but is still cached")
+	}
+	if _, ok := tc.get(keyB); !ok {
+		t.Error("keyB is the most recently put entry and should still be cached")
+	}
+}
+
+// TestThumbCachePutOverwritesExistingKey covers the duplicate-key branch of
+// put, which must replace the cached pixbuf for a key rather than leaking a
+// second entry into the index.
+func TestThumbCachePutOverwritesExistingKey(t *testing.T) {
+	tc := &thumbCache{
+		lru:      list.New(),
+		index:    make(map[thumbKey]*list.Element),
+		maxBytes: 1 << 30,
+	}
+
+	key := thumbKey{path: "a.jpg", destWidth: 4, destHeight: 4}
+	tc.put(key, newTestPixbuf(t, 4, 4))
+	tc.put(key, newTestPixbuf(t, 4, 4))
+
+	if len(tc.index) != 1 {
+		t.Fatalf("index has %d entries, want 1 after overwriting the same key", len(tc.index))
+	}
+}
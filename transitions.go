@@ -0,0 +1,165 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gotk3/gotk3/cairo"
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+func easeInOutQuad(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - math.Pow(-2*t+2, 2)/2
+}
+
+// transitionEngine drives the none/crossfade/kenburns slide transition. It
+// owns the from/to pixbufs and renders the current animation frame into a
+// gtk.DrawingArea via Cairo on a ~16ms tick.
+type transitionEngine struct {
+	area       *gtk.DrawingArea
+	mode       string
+	durationMs uint
+
+	mu        sync.Mutex
+	from      *gdk.Pixbuf
+	to        *gdk.Pixbuf
+	startedAt time.Time
+	running   bool
+
+	kenburnsFromScale, kenburnsToScale float64
+	kenburnsFromX, kenburnsFromY       float64
+	kenburnsToX, kenburnsToY           float64
+
+	tickID glib.SourceHandle
+}
+
+func newTransitionEngine(area *gtk.DrawingArea, mode string, durationMs uint) *transitionEngine {
+	te := &transitionEngine{area: area, mode: mode, durationMs: durationMs}
+
+	area.Connect("draw", func(da *gtk.DrawingArea, cr *cairo.Context) {
+		te.draw(cr, float64(da.GetAllocatedWidth()), float64(da.GetAllocatedHeight()))
+	})
+
+	return te
+}
+
+// goTo starts a transition to pixbuf from whatever is currently displayed.
+// Calling it again mid-transition (e.g. the user pressing Right/Left twice
+// in quick succession) drops the unfinished animation: the previous target
+// becomes the starting slide and a new transition to pixbuf begins, so no
+// stale state is left behind.
+func (te *transitionEngine) goTo(pixbuf *gdk.Pixbuf) {
+	te.mu.Lock()
+
+	if te.mode == "none" {
+		te.from = pixbuf
+		te.to = pixbuf
+		te.running = false
+		te.mu.Unlock()
+		te.area.QueueDraw()
+		return
+	}
+
+	if te.to != nil {
+		te.from = te.to
+	}
+	te.to = pixbuf
+	te.startedAt = time.Now()
+	te.running = true
+
+	if te.mode == "kenburns" {
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		te.kenburnsFromScale = 1.0
+		te.kenburnsToScale = 1.05 + rnd.Float64()*0.1
+		te.kenburnsFromX, te.kenburnsFromY = 0, 0
+		te.kenburnsToX = (rnd.Float64() - 0.5) * 0.1
+		te.kenburnsToY = (rnd.Float64() - 0.5) * 0.1
+	}
+
+	te.mu.Unlock()
+
+	if te.tickID == 0 {
+		te.tickID = glib.TimeoutAdd(16, te.tick)
+	}
+}
+
+func (te *transitionEngine) tick() bool {
+	te.mu.Lock()
+	running := te.running
+	te.mu.Unlock()
+
+	te.area.QueueDraw()
+
+	if !running {
+		te.tickID = 0
+		return false
+	}
+
+	return true
+}
+
+func (te *transitionEngine) draw(cr *cairo.Context, width, height float64) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	cr.SetSourceRGB(0, 0, 0)
+	cr.Paint()
+
+	if te.to == nil {
+		return
+	}
+
+	progress := 1.0
+	if te.running {
+		progress = float64(time.Since(te.startedAt).Milliseconds()) / float64(te.durationMs)
+		if progress >= 1.0 {
+			progress = 1.0
+			te.running = false
+			te.from = te.to
+		}
+	}
+
+	eased := easeInOutQuad(progress)
+
+	switch te.mode {
+	case "crossfade":
+		if te.from != nil && te.from != te.to {
+			drawPixbufCentered(cr, te.from, width, height, 1.0)
+		}
+		drawPixbufCentered(cr, te.to, width, height, eased)
+	case "kenburns":
+		scale := te.kenburnsFromScale + (te.kenburnsToScale-te.kenburnsFromScale)*eased
+		panX := te.kenburnsFromX + (te.kenburnsToX-te.kenburnsFromX)*eased
+		panY := te.kenburnsFromY + (te.kenburnsToY-te.kenburnsFromY)*eased
+		drawPixbufKenBurns(cr, te.to, width, height, scale, panX, panY)
+	default:
+		drawPixbufCentered(cr, te.to, width, height, 1.0)
+	}
+}
+
+func drawPixbufCentered(cr *cairo.Context, pixbuf *gdk.Pixbuf, areaWidth, areaHeight, alpha float64) {
+	x := (areaWidth - float64(pixbuf.GetWidth())) / 2
+	y := (areaHeight - float64(pixbuf.GetHeight())) / 2
+
+	gdk.CairoSetSourcePixbuf(cr, pixbuf, x, y)
+	cr.PaintWithAlpha(alpha)
+}
+
+func drawPixbufKenBurns(cr *cairo.Context, pixbuf *gdk.Pixbuf, areaWidth, areaHeight, scale, panX, panY float64) {
+	cr.Save()
+	defer cr.Restore()
+
+	cr.Translate(areaWidth/2+panX*areaWidth, areaHeight/2+panY*areaHeight)
+	cr.Scale(scale, scale)
+	cr.Translate(-float64(pixbuf.GetWidth())/2, -float64(pixbuf.GetHeight())/2)
+
+	gdk.CairoSetSourcePixbuf(cr, pixbuf, 0, 0)
+	cr.Paint()
+}
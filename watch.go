@@ -0,0 +1,174 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gotk3/gotk3/glib"
+)
+
+const watchDebounce = 500 * time.Millisecond
+
+// reloadWatcher watches ManifestPath and ContentDirectory for changes and
+// re-runs manifestSource.Load / listImages when they settle, delivering the
+// result back onto the GTK main thread via glib.IdleAdd. Bursts of events
+// (e.g. a bulk copy into the content directory) are coalesced with a short
+// debounce so a single reload runs instead of one per file.
+type reloadWatcher struct {
+	manifestPath   string
+	contentDir     string
+	manifestSource ManifestSource
+	isRandomOrder  bool
+
+	onManifestReloaded func(*Manifest)
+	onImagesReloaded   func([]string)
+
+	watcher *fsnotify.Watcher
+	timer   *time.Timer
+
+	// manifestTouchedMu guards manifestTouchedInWindow, which is written
+	// from rw.loop's goroutine and read/reset from the time.AfterFunc
+	// timer's own goroutine.
+	manifestTouchedMu sync.Mutex
+	// manifestTouchedInWindow accumulates whether any event in the current
+	// debounce window touched the manifest, so a manifest edit isn't
+	// dropped by a later burst of unrelated content-dir events overwriting
+	// it before the timer fires.
+	manifestTouchedInWindow bool
+
+	// manifest is the last manifest loaded, kept around so a content-only
+	// reload can still resolve images for a DesktopSource (whose images
+	// come from manifest.Entries, not a content-directory walk).
+	manifest *Manifest
+}
+
+func newReloadWatcher(manifestPath, contentDir string, manifestSource ManifestSource, initialManifest *Manifest, isRandomOrder bool, onManifestReloaded func(*Manifest), onImagesReloaded func([]string)) (*reloadWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	rw := &reloadWatcher{
+		manifestPath:       manifestPath,
+		contentDir:         contentDir,
+		manifestSource:     manifestSource,
+		isRandomOrder:      isRandomOrder,
+		onManifestReloaded: onManifestReloaded,
+		onImagesReloaded:   onImagesReloaded,
+		watcher:            watcher,
+		manifest:           initialManifest,
+	}
+
+	if err := watcher.Add(filepath.Dir(manifestPath)); err != nil {
+		return nil, err
+	}
+
+	if err := filepath.Walk(contentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	go rw.loop()
+
+	return rw, nil
+}
+
+func (rw *reloadWatcher) loop() {
+	for {
+		select {
+		case event, ok := <-rw.watcher.Events:
+			if !ok {
+				return
+			}
+			rw.watchIfNewDir(event)
+			rw.scheduleReload(event)
+		case err, ok := <-rw.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("watch: %v", err)
+		}
+	}
+}
+
+// watchIfNewDir adds a watch for event.Name when it's a freshly created
+// directory under contentDir. fsnotify doesn't watch subdirectories
+// recursively or pick up directories created after the initial Add, so
+// without this a bulk copy into a brand new subfolder would go unnoticed
+// until the process restarts.
+func (rw *reloadWatcher) watchIfNewDir(event fsnotify.Event) {
+	if event.Op&fsnotify.Create == 0 {
+		return
+	}
+
+	info, err := os.Stat(event.Name)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	if err := rw.watcher.Add(event.Name); err != nil {
+		log.Printf("watch: failed to watch new directory %s: %v", event.Name, err)
+	}
+}
+
+func (rw *reloadWatcher) scheduleReload(event fsnotify.Event) {
+	if rw.timer != nil {
+		rw.timer.Stop()
+	}
+
+	if event.Name == rw.manifestPath || filepath.Dir(event.Name) == filepath.Dir(rw.manifestPath) {
+		rw.manifestTouchedMu.Lock()
+		rw.manifestTouchedInWindow = true
+		rw.manifestTouchedMu.Unlock()
+	}
+
+	rw.timer = time.AfterFunc(watchDebounce, func() {
+		rw.manifestTouchedMu.Lock()
+		manifestTouched := rw.manifestTouchedInWindow
+		rw.manifestTouchedInWindow = false
+		rw.manifestTouchedMu.Unlock()
+		rw.reload(manifestTouched)
+	})
+}
+
+func (rw *reloadWatcher) reload(manifestTouched bool) {
+	if manifestTouched {
+		manifest, err := rw.manifestSource.Load(rw.manifestPath)
+		if err != nil {
+			log.Printf("watch: failed to reload manifest: %v", err)
+		} else {
+			resolveManifestEntryImages(manifest)
+			rw.manifest = manifest
+			glib.IdleAdd(func() bool {
+				rw.onManifestReloaded(manifest)
+				return false
+			})
+		}
+	}
+
+	images, err := imagesForManifest(rw.manifestSource, rw.manifest, rw.contentDir, rw.isRandomOrder)
+	if err != nil {
+		log.Printf("watch: failed to reload images: %v", err)
+		return
+	}
+
+	glib.IdleAdd(func() bool {
+		rw.onImagesReloaded(images)
+		return false
+	})
+}
+
+func (rw *reloadWatcher) close() {
+	rw.watcher.Close()
+}